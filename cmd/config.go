@@ -1,11 +1,30 @@
 package cmd
 
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/mitchellh/mapstructure"
+)
+
 // Ports is used to simplify the configuration by
 // providing default ports, and allowing the addresses
 // to only be specified once
 type PortConfig struct {
 	HTTP   int // HTTP API
 	Server int // Server internal RPC
+
+	// Metrics is the Prometheus /metrics scrape endpoint. It runs its
+	// own net.Listener (see startMetricsServer) independent of the HTTP
+	// API's, so it needs its own port - binding it to Ports.HTTP would
+	// collide with the HTTP API's listener whenever both are enabled.
+	Metrics int
 }
 
 // Config is the configuration that can be set for an Agent.
@@ -32,6 +51,55 @@ type Config struct {
 	// addresses, then the agent will error and exit.
 	StartJoin []string `mapstructure:"start_join"`
 
+	// RetryJoin is like StartJoin but is retried in the background on a
+	// RetryInterval until it succeeds or RetryMaxAttempts is reached,
+	// rather than failing the agent on startup. Entries may also be
+	// "provider=..." discovery strings (e.g. "provider=aws tag_key=foo
+	// tag_value=bar region=us-east-1") that are resolved to a list of
+	// peer addresses at join time.
+	RetryJoin []string `mapstructure:"retry_join"`
+
+	// RetryMaxAttempts caps the number of RetryJoin attempts. 0 means
+	// retry forever.
+	RetryMaxAttempts int `mapstructure:"retry_max_attempts"`
+
+	// RetryInterval is the time to wait between RetryJoin attempts.
+	RetryInterval time.Duration `mapstructure:"retry_interval"`
+
+	// Telemetry is the configuration for the metrics sinks the agent
+	// reports to.
+	Telemetry Telemetry `mapstructure:"telemetry"`
+
+	// LogFile, if set, is an additional destination log output is
+	// written to alongside stderr.
+	LogFile string `mapstructure:"log_file"`
+
+	// LogRotateBytes rotates LogFile once it exceeds this size. 0
+	// disables rotation.
+	LogRotateBytes int64 `mapstructure:"log_rotate_bytes"`
+
+	// EnableSyslog enables logging to syslog, on platforms that support
+	// it.
+	EnableSyslog bool `mapstructure:"enable_syslog"`
+
+	// SyslogFacility is the syslog facility to use when EnableSyslog is
+	// set, e.g. "LOCAL0".
+	SyslogFacility string `mapstructure:"syslog_facility"`
+
+	// SkipLeaveOnInt controls whether a SIGINT triggers a graceful
+	// Leave before shutdown. Defaults to false (i.e. SIGINT does leave).
+	SkipLeaveOnInt bool `mapstructure:"skip_leave_on_interrupt"`
+
+	// LeaveOnTerm controls whether a SIGTERM triggers a graceful Leave
+	// before shutdown. Defaults to false, since SIGTERM is commonly sent
+	// by process supervisors expecting a fast exit.
+	LeaveOnTerm bool `mapstructure:"leave_on_terminate"`
+
+	// LeaveDrainTimeout bounds how long a graceful Leave is given to
+	// finish (deregistering services, gossiping a leave intent) before
+	// shutdown is forced anyway.
+	LeaveDrainTimeout time.Duration `mapstructure:"leave_drain_timeout"`
+
 	// Revision is the GitCommit this maps to
 	Revision string `mapstructure:"-"`
 
@@ -42,14 +110,268 @@ type Config struct {
 	VersionPrerelease string `mapstructure:"-"`
 }
 
+// ReloadableConfig holds the subset of Config that can be changed with a
+// SIGHUP/config reload while the agent is running, without requiring a
+// restart. handleReload rejects a reload that changes BindAddr, Ports or
+// NodeName; changes to any other field not present here are ignored
+// until the agent is restarted.
+type ReloadableConfig struct {
+	LogLevel  string
+	Telemetry Telemetry
+}
+
+// Reloadable extracts the hot-reloadable portion of the config, for
+// handleReload to apply on top of the running Config.
+func (c *Config) Reloadable() *ReloadableConfig {
+	return &ReloadableConfig{
+		LogLevel:  c.LogLevel,
+		Telemetry: c.Telemetry,
+	}
+}
+
+// Telemetry is the telemetry configuration for the agent, controlling
+// which metrics sinks (beyond the always-on in-memory sink) metrics are
+// fanned out to.
+type Telemetry struct {
+	// StatsiteAddr is the address of a statsite instance to stream
+	// metrics to through TCP.
+	StatsiteAddr string `mapstructure:"statsite_address"`
+
+	// StatsdAddr is the address of a statsd instance to stream metrics
+	// to through UDP.
+	StatsdAddr string `mapstructure:"statsd_address"`
+
+	// DogStatsdAddr is the address of a dogstatsd instance to stream
+	// metrics to through UDP.
+	DogStatsdAddr string `mapstructure:"dogstatsd_addr"`
+
+	// DogStatsdTags are global tags appended to every metric sent to
+	// dogstatsd.
+	DogStatsdTags []string `mapstructure:"dogstatsd_tags"`
+
+	// DisableHostname disables prepending the node hostname to metric
+	// names.
+	DisableHostname bool `mapstructure:"disable_hostname"`
+
+	// MetricsPrefix is prepended to all metric names. Defaults to
+	// "consul" when unset.
+	MetricsPrefix string `mapstructure:"metrics_prefix"`
+}
+
 // DefaultConfig is used to return a sane default configuration
 func DefaultConfig() *Config {
 	return &Config{
 		LogLevel: "INFO",
 		BindAddr: "0.0.0.0",
 		Ports: PortConfig{
-			HTTP:   8500,
-			Server: 8300,
+			HTTP:    8500,
+			Server:  8300,
+			Metrics: 8502,
 		},
+		RetryInterval:     30 * time.Second,
+		SyslogFacility:    "LOCAL0",
+		LeaveDrainTimeout: 5 * time.Second,
+	}
+}
+
+// ReadConfig parses either JSON or HCL from r into a Config. Since both
+// formats decode to the same generic map shape, we let hcl.Decode handle
+// both (it is a superset parser) and then map the result onto Config via
+// mapstructure, honoring the `mapstructure` tags already on the struct.
+func ReadConfig(r io.Reader) (*Config, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %s", err)
+	}
+
+	var raw_ interface{}
+	if err := hcl.Decode(&raw_, string(raw)); err != nil {
+		return nil, fmt.Errorf("error decoding config: %s", err)
 	}
+
+	var result Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		Result: &result,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decoding config: %s", err)
+	}
+	if err := decoder.Decode(raw_); err != nil {
+		return nil, fmt.Errorf("error decoding config: %s", err)
+	}
+
+	return &result, nil
+}
+
+// ReadConfigPaths reads the paths in the given order to load configuration.
+// Paths that are directories are walked, loading *.json and *.hcl files in
+// lexical order. The resulting configs are merged in the order they were
+// encountered, with later files taking precedence over earlier ones.
+func ReadConfigPaths(paths []string) (*Config, error) {
+	result := new(Config)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading '%s': %s", path, err)
+		}
+
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("error reading '%s': %s", path, err)
+		}
+
+		if !fi.IsDir() {
+			config, err := ReadConfig(f)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("error reading '%s': %s", path, err)
+			}
+
+			result = MergeConfig(result, config)
+			continue
+		}
+
+		// Read the directory's own *.json and *.hcl files in lexical
+		// order (subdirectories are skipped), mirroring how Consul's own config
+		// directory loading behaves.
+		contents, err := f.Readdir(-1)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading '%s': %s", path, err)
+		}
+
+		var files []string
+		for _, fi := range contents {
+			if fi.IsDir() {
+				continue
+			}
+
+			name := fi.Name()
+			skip := true
+			if strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".hcl") {
+				skip = false
+			}
+			if skip || isTemporaryFile(name) {
+				continue
+			}
+
+			files = append(files, filepath.Join(path, name))
+		}
+		sort.Strings(files)
+
+		for _, p := range files {
+			config, err := ReadConfigPaths([]string{p})
+			if err != nil {
+				return nil, err
+			}
+
+			result = MergeConfig(result, config)
+		}
+	}
+
+	return result, nil
+}
+
+// isTemporaryFile returns true or false depending on whether the
+// provided file name is a temporary file for the following editors:
+// emacs or vim.
+func isTemporaryFile(name string) bool {
+	return strings.HasSuffix(name, "~") || // vim
+		strings.HasPrefix(name, ".#") || // emacs
+		(strings.HasPrefix(name, "#") && strings.HasSuffix(name, "#")) // emacs
+}
+
+// MergeConfig merges two configurations together, with b taking precedence
+// over a for any field that b sets to a non-zero value. Slices are
+// appended rather than replaced, and nested structs are merged field by
+// field so that, e.g., specifying only Ports.HTTP in b does not clobber
+// Ports.Server from a.
+func MergeConfig(a, b *Config) *Config {
+	result := *a
+
+	if b.LogLevel != "" {
+		result.LogLevel = b.LogLevel
+	}
+	if b.NodeName != "" {
+		result.NodeName = b.NodeName
+	}
+	if b.BindAddr != "" {
+		result.BindAddr = b.BindAddr
+	}
+	if b.Ports.HTTP != 0 {
+		result.Ports.HTTP = b.Ports.HTTP
+	}
+	if b.Ports.Server != 0 {
+		result.Ports.Server = b.Ports.Server
+	}
+	if b.Ports.Metrics != 0 {
+		result.Ports.Metrics = b.Ports.Metrics
+	}
+	if b.Revision != "" {
+		result.Revision = b.Revision
+	}
+	if b.Version != "" {
+		result.Version = b.Version
+	}
+	if b.VersionPrerelease != "" {
+		result.VersionPrerelease = b.VersionPrerelease
+	}
+
+	// Slices are merged by appending, so that e.g. -config-dir files can
+	// each contribute additional join addresses.
+	result.StartJoin = append(result.StartJoin, b.StartJoin...)
+	result.RetryJoin = append(result.RetryJoin, b.RetryJoin...)
+
+	if b.RetryMaxAttempts != 0 {
+		result.RetryMaxAttempts = b.RetryMaxAttempts
+	}
+	if b.RetryInterval != 0 {
+		result.RetryInterval = b.RetryInterval
+	}
+
+	if b.Telemetry.StatsiteAddr != "" {
+		result.Telemetry.StatsiteAddr = b.Telemetry.StatsiteAddr
+	}
+	if b.Telemetry.StatsdAddr != "" {
+		result.Telemetry.StatsdAddr = b.Telemetry.StatsdAddr
+	}
+	if b.Telemetry.DogStatsdAddr != "" {
+		result.Telemetry.DogStatsdAddr = b.Telemetry.DogStatsdAddr
+	}
+	result.Telemetry.DogStatsdTags = append(result.Telemetry.DogStatsdTags, b.Telemetry.DogStatsdTags...)
+	if b.Telemetry.DisableHostname {
+		result.Telemetry.DisableHostname = b.Telemetry.DisableHostname
+	}
+	if b.Telemetry.MetricsPrefix != "" {
+		result.Telemetry.MetricsPrefix = b.Telemetry.MetricsPrefix
+	}
+
+	if b.LogFile != "" {
+		result.LogFile = b.LogFile
+	}
+	if b.LogRotateBytes != 0 {
+		result.LogRotateBytes = b.LogRotateBytes
+	}
+	if b.EnableSyslog {
+		result.EnableSyslog = b.EnableSyslog
+	}
+	if b.SyslogFacility != "" {
+		result.SyslogFacility = b.SyslogFacility
+	}
+	if b.SkipLeaveOnInt {
+		result.SkipLeaveOnInt = b.SkipLeaveOnInt
+	}
+	if b.LeaveOnTerm {
+		result.LeaveOnTerm = b.LeaveOnTerm
+	}
+	if b.LeaveDrainTimeout != 0 {
+		result.LeaveDrainTimeout = b.LeaveDrainTimeout
+	}
+
+	return &result
 }
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeConfig_ScalarPrecedence(t *testing.T) {
+	a := &Config{LogLevel: "INFO", NodeName: "a-node", BindAddr: "0.0.0.0"}
+	b := &Config{LogLevel: "DEBUG"}
+
+	result := MergeConfig(a, b)
+
+	if result.LogLevel != "DEBUG" {
+		t.Fatalf("expected b's non-zero LogLevel to win, got %q", result.LogLevel)
+	}
+	if result.NodeName != "a-node" {
+		t.Fatalf("expected a's NodeName to survive when b leaves it zero, got %q", result.NodeName)
+	}
+	if result.BindAddr != "0.0.0.0" {
+		t.Fatalf("expected a's BindAddr to survive when b leaves it zero, got %q", result.BindAddr)
+	}
+}
+
+func TestMergeConfig_NestedPortConfig(t *testing.T) {
+	a := &Config{Ports: PortConfig{HTTP: 8500, Server: 8300}}
+	b := &Config{Ports: PortConfig{HTTP: 9500}}
+
+	result := MergeConfig(a, b)
+
+	if result.Ports.HTTP != 9500 {
+		t.Fatalf("expected b's Ports.HTTP to win, got %d", result.Ports.HTTP)
+	}
+	if result.Ports.Server != 8300 {
+		t.Fatalf("expected a's Ports.Server to survive when b leaves it zero, got %d", result.Ports.Server)
+	}
+}
+
+func TestMergeConfig_NestedTelemetry(t *testing.T) {
+	a := &Config{Telemetry: Telemetry{
+		StatsiteAddr:  "10.0.0.1:8125",
+		MetricsPrefix: "a-prefix",
+		DogStatsdTags: []string{"env:a"},
+	}}
+	b := &Config{Telemetry: Telemetry{
+		DogStatsdAddr: "10.0.0.2:8125",
+		DogStatsdTags: []string{"env:b"},
+	}}
+
+	result := MergeConfig(a, b)
+
+	if result.Telemetry.StatsiteAddr != "10.0.0.1:8125" {
+		t.Fatalf("expected a's StatsiteAddr to survive when b leaves it zero, got %q", result.Telemetry.StatsiteAddr)
+	}
+	if result.Telemetry.DogStatsdAddr != "10.0.0.2:8125" {
+		t.Fatalf("expected b's DogStatsdAddr to win, got %q", result.Telemetry.DogStatsdAddr)
+	}
+	if result.Telemetry.MetricsPrefix != "a-prefix" {
+		t.Fatalf("expected a's MetricsPrefix to survive when b leaves it zero, got %q", result.Telemetry.MetricsPrefix)
+	}
+
+	wantTags := []string{"env:a", "env:b"}
+	if !reflect.DeepEqual(result.Telemetry.DogStatsdTags, wantTags) {
+		t.Fatalf("expected DogStatsdTags to append in order, got %v", result.Telemetry.DogStatsdTags)
+	}
+}
+
+func TestMergeConfig_SlicesAppend(t *testing.T) {
+	a := &Config{StartJoin: []string{"10.0.0.1"}, RetryJoin: []string{"10.0.0.2"}}
+	b := &Config{StartJoin: []string{"10.0.0.3"}, RetryJoin: []string{"provider=aws tag_key=foo"}}
+
+	result := MergeConfig(a, b)
+
+	wantStartJoin := []string{"10.0.0.1", "10.0.0.3"}
+	if !reflect.DeepEqual(result.StartJoin, wantStartJoin) {
+		t.Fatalf("expected StartJoin to append in order, got %v", result.StartJoin)
+	}
+
+	wantRetryJoin := []string{"10.0.0.2", "provider=aws tag_key=foo"}
+	if !reflect.DeepEqual(result.RetryJoin, wantRetryJoin) {
+		t.Fatalf("expected RetryJoin to append in order, got %v", result.RetryJoin)
+	}
+}
+
+func TestMergeConfig_DurationAndBoolPrecedence(t *testing.T) {
+	a := &Config{RetryInterval: 30 * time.Second, LeaveOnTerm: false}
+	b := &Config{RetryInterval: 5 * time.Second, LeaveOnTerm: true}
+
+	result := MergeConfig(a, b)
+
+	if result.RetryInterval != 5*time.Second {
+		t.Fatalf("expected b's non-zero RetryInterval to win, got %s", result.RetryInterval)
+	}
+	if !result.LeaveOnTerm {
+		t.Fatal("expected b's LeaveOnTerm=true to win over a's false")
+	}
+}
+
+func TestMergeConfig_DoesNotMutateInputs(t *testing.T) {
+	a := &Config{LogLevel: "INFO", StartJoin: []string{"10.0.0.1"}}
+	b := &Config{LogLevel: "DEBUG"}
+
+	_ = MergeConfig(a, b)
+
+	if a.LogLevel != "INFO" {
+		t.Fatalf("MergeConfig must not mutate its a argument, got LogLevel %q", a.LogLevel)
+	}
+	if len(a.StartJoin) != 1 {
+		t.Fatalf("MergeConfig must not mutate a's StartJoin slice in place, got %v", a.StartJoin)
+	}
+}
@@ -0,0 +1,20 @@
+package cmd
+
+// AppendSliceValue implements the flag.Value interface and allows multiple
+// calls to the same variable to append a list, rather than clobbering
+// the previous value. This is used to allow flags like
+// "-config-file" or "-config-dir" to be specified multiple times.
+type AppendSliceValue []string
+
+func (s *AppendSliceValue) String() string {
+	return ""
+}
+
+func (s *AppendSliceValue) Set(value string) error {
+	if *s == nil {
+		*s = make([]string, 0, 1)
+	}
+
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	discover "github.com/hashicorp/go-discover"
+	_ "github.com/hashicorp/go-discover/provider/aws"
+	_ "github.com/hashicorp/go-discover/provider/gce"
+)
+
+// startupJoin attempts a one-shot join against config.StartJoin. Unlike
+// retryJoin below, failing to reach any of these addresses is fatal to
+// agent startup.
+func (c *ServiceCommand) startupJoin(config *Config) error {
+	if len(config.StartJoin) == 0 {
+		return nil
+	}
+
+	c.Ui.Output("Joining cluster...")
+	n, err := c.agent.Join(config.StartJoin, false)
+	if err != nil {
+		return err
+	}
+
+	c.Ui.Info(fmt.Sprintf("Join completed. Synced with %d initial agents", n))
+	return nil
+}
+
+// retryJoin runs in the background for the life of the agent, retrying
+// config.RetryJoin (expanding any "provider=..." discovery strings along
+// the way) on RetryInterval until it succeeds or RetryMaxAttempts is
+// exhausted. A RetryJoin failure never stops the agent from serving. It
+// watches c.ShutdownCh so it exits promptly once that channel is closed
+// instead of sleeping through it or calling c.agent.Join afterwards. A
+// shutdown started by SIGINT/SIGTERM in handleShutdown does not close
+// c.ShutdownCh, so it does not stop this loop.
+func (c *ServiceCommand) retryJoin(config *Config) {
+	if len(config.RetryJoin) == 0 {
+		return
+	}
+
+	attempt := 0
+	for {
+		select {
+		case <-c.ShutdownCh:
+			return
+		default:
+		}
+
+		addrs, err := c.discoverJoinAddrs(config.RetryJoin)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Retry join address resolution failed: %v", err))
+		} else if len(addrs) > 0 {
+			n, err := c.agent.Join(addrs, false)
+			if err == nil {
+				c.Ui.Info(fmt.Sprintf("Join completed. Synced with %d initial agents", n))
+				return
+			}
+			c.Ui.Error(fmt.Sprintf("Retry join failed: %v", err))
+		}
+
+		attempt++
+		if config.RetryMaxAttempts > 0 && attempt >= config.RetryMaxAttempts {
+			c.Ui.Error("Retry join failed: reached maximum retry attempts, giving up")
+			return
+		}
+
+		select {
+		case <-time.After(config.RetryInterval):
+		case <-c.ShutdownCh:
+			return
+		}
+	}
+}
+
+// discoverJoinAddrs resolves a list of join addresses, expanding any
+// "provider=..." discovery string (e.g. "provider=aws tag_key=foo
+// tag_value=bar region=us-east-1") into the peer addresses returned by
+// the matching cloud provider API. Plain addresses are passed through
+// unchanged.
+func (c *ServiceCommand) discoverJoinAddrs(addrs []string) ([]string, error) {
+	var result []string
+	d := discover.Discover{}
+	for _, addr := range addrs {
+		if !strings.Contains(addr, "provider=") {
+			result = append(result, addr)
+			continue
+		}
+
+		found, err := d.Addrs(addr, log.New(os.Stderr, "", log.LstdFlags))
+		if err != nil {
+			return nil, fmt.Errorf("discovery for %q failed: %v", addr, err)
+		}
+
+		result = append(result, found...)
+	}
+
+	return result, nil
+}
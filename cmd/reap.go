@@ -0,0 +1,58 @@
+package cmd
+
+import "sync"
+
+// reapResult carries the exit status for a directly-managed child back
+// to whichever code spawned it (e.g. a health check exec plugin).
+type reapResult struct {
+	exitStatus int
+	err        error
+}
+
+var (
+	reapWaitersMu sync.Mutex
+	reapWaiters   = map[int]chan reapResult{}
+)
+
+// trackManagedPID registers pid as directly spawned by the caller, as
+// opposed to a reparented orphan. The subreaper is the only goroutine
+// that ever calls wait4, so the caller must receive on the returned
+// channel instead of calling its own Wait/Wait4 for pid - otherwise the
+// subreaper's blanket wait4(-1) can steal the exit status out from under
+// it. Call untrackManagedPID if the caller gives up waiting.
+func trackManagedPID(pid int) <-chan reapResult {
+	ch := make(chan reapResult, 1)
+	reapWaitersMu.Lock()
+	reapWaiters[pid] = ch
+	reapWaitersMu.Unlock()
+	return ch
+}
+
+// untrackManagedPID removes a pid registered with trackManagedPID that
+// no longer needs to be waited on.
+func untrackManagedPID(pid int) {
+	reapWaitersMu.Lock()
+	delete(reapWaiters, pid)
+	reapWaitersMu.Unlock()
+}
+
+// deliverReapResult hands a reaped pid's status to its registered
+// waiter, if any. It reports whether pid was actually a tracked,
+// directly-managed child (true) as opposed to a reparented orphan
+// (false), which the subreaper uses to decide whether it owns the
+// status or just reaped an orphan nobody else is watching.
+func deliverReapResult(pid int, exitStatus int, err error) bool {
+	reapWaitersMu.Lock()
+	ch, ok := reapWaiters[pid]
+	if ok {
+		delete(reapWaiters, pid)
+	}
+	reapWaitersMu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	ch <- reapResult{exitStatus: exitStatus, err: err}
+	return true
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// startReaper spawns a background goroutine that reaps orphaned child
+// processes when running as PID 1, the container use case where nothing
+// else on the system will wait() on them. Without this, health check
+// exec plugins that outlive their check interval accumulate as zombies.
+//
+// wait4(-1, ...) reaps whichever child is ready, including ones this
+// process spawned directly (e.g. a running health check exec plugin),
+// not just reparented orphans. If we reaped one of those ourselves and
+// just discarded the status, the code that spawned it (os/exec's
+// cmd.Wait, which issues its own wait4 for that specific pid) would get
+// ECHILD instead of the real exit status. So directly-managed children
+// must register with trackManagedPID before this loop can see them;
+// when wait4 reaps one of those we hand the status back over its
+// channel instead of dropping it, and only treat genuinely unclaimed
+// pids as orphans.
+func (c *ServiceCommand) startReaper() {
+	if os.Getpid() != 1 {
+		return
+	}
+
+	go func() {
+		sigCh := make(chan os.Signal, 4)
+		signal.Notify(sigCh, syscall.SIGCHLD)
+
+		for range sigCh {
+			for {
+				var status syscall.WaitStatus
+				pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+				if pid <= 0 || err != nil {
+					break
+				}
+
+				if !deliverReapResult(pid, status.ExitStatus(), nil) {
+					// No registered waiter claimed this pid, so it was
+					// a genuinely reparented orphan rather than a
+					// directly-managed child - log it so an unexpected
+					// flood of orphans is visible instead of silently
+					// vanishing.
+					log.Printf("[DEBUG] agent: reaped orphaned child process %d (status %d)", pid, status.ExitStatus())
+				}
+			}
+		}
+	}()
+}
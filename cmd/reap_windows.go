@@ -0,0 +1,7 @@
+//go:build windows
+
+package cmd
+
+// startReaper is a no-op on Windows, which has no notion of PID 1
+// subreaping.
+func (c *ServiceCommand) startReaper() {}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// handleReload re-reads the same -config-file/-config-dir set the agent
+// was started with, validates that none of the immutable fields changed,
+// and applies the hot-reloadable fields from ReloadableConfig onto the
+// running config in place. It returns the updated config, or config
+// unchanged and an error if the re-read fails, an immutable field changed
+// or the new log level is invalid. If rebuilding the telemetry sinks
+// fails, the new log level and Telemetry settings have already been
+// applied to config when the error is returned.
+//
+// NOTE: services, checks, and watches are not reloaded here. Nothing in
+// this tree defines a registry for them on Config yet (config.Services /
+// config.Checks referenced from Run are stubs from a package this
+// snapshot doesn't include); wiring their hot-reload belongs with
+// whatever change actually introduces those registries.
+func (c *ServiceCommand) handleReload(config *Config) (*Config, error) {
+	c.Ui.Output("Reloading configuration...")
+
+	newConf, err := c.mergedConfig()
+	if err != nil {
+		return config, fmt.Errorf("Failed to reload configs: %s", err)
+	}
+	if newConf.NodeName == "" {
+		// NodeName isn't reloadable and mergedConfig doesn't re-derive
+		// the hostname fallback readConfig applies at startup; inherit
+		// the running value instead of comparing against a spurious
+		// empty one below.
+		newConf.NodeName = config.NodeName
+	}
+
+	if newConf.BindAddr != config.BindAddr {
+		return config, fmt.Errorf("BindAddr cannot be changed via reload, restart required")
+	}
+	if newConf.Ports != config.Ports {
+		return config, fmt.Errorf("Ports cannot be changed via reload, restart required")
+	}
+	if newConf.NodeName != config.NodeName {
+		return config, fmt.Errorf("NodeName cannot be changed via reload, restart required")
+	}
+
+	reloadable := newConf.Reloadable()
+	if c.logFilter != nil {
+		if err := c.logFilter.SetMinLevel(reloadable.LogLevel); err != nil {
+			return config, err
+		}
+	}
+	config.LogLevel = reloadable.LogLevel
+
+	// Telemetry is genuinely live: rebuild the sinks (and the global
+	// metrics instance they back) rather than just updating the
+	// struct, so changed tags/addresses actually take effect. Only do
+	// so when it actually changed - setupTelemetry tears down and
+	// reopens every remote sink's connection, so rebuilding on every
+	// SIGHUP regardless would needlessly drop and reconnect statsite/
+	// statsd/dogstatsd on an unrelated reload (e.g. just a log level
+	// change).
+	if !reflect.DeepEqual(reloadable.Telemetry, config.Telemetry) {
+		config.Telemetry = reloadable.Telemetry
+		inm, err := c.setupTelemetry(config)
+		if err != nil {
+			return config, fmt.Errorf("Failed to apply reloaded telemetry config: %s", err)
+		}
+		c.inmemSink = inm
+	}
+
+	return config, nil
+}
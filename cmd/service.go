@@ -3,11 +3,17 @@ package cmd
 import (
 	"flag"
 	"fmt"
-	"github.com/mitchellh/cli"
-	"golang.org/x/exp/slog"
+	"io"
+	"net/http"
 	"os"
 	"runtime"
-	"time"
+	"strings"
+
+	"github.com/LasseJacobs/groupcache/logger"
+	"github.com/armon/go-metrics"
+	"github.com/mitchellh/cli"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/exp/slog"
 )
 
 type ServiceCommand struct {
@@ -17,6 +23,44 @@ type ServiceCommand struct {
 	Ui                cli.Ui
 	ShutdownCh        <-chan struct{}
 	args              []string
+
+	// configFiles is the -config-file/-config-dir set the agent was
+	// started with, retained so a SIGHUP reload can re-read the same
+	// paths.
+	configFiles []string
+
+	// cmdConfig holds the CLI-flag overrides the agent was started
+	// with, retained so a SIGHUP reload can reapply them on top of the
+	// re-read files instead of silently dropping back to file/default
+	// values.
+	cmdConfig *Config
+
+	// configReloadCh lets callers request a reload programmatically: send
+	// a reply channel and handleReload's error (nil on success) comes back
+	// on it. SIGHUP drives the same path without a reply channel.
+	configReloadCh chan chan error
+
+	// logFilter gates log output by level and is mutated in place by
+	// handleReload so a SIGHUP can change the running log level.
+	logFilter *logger.LevelFilter
+
+	// inmemSink backs the SIGUSR1 metrics dump. The Prometheus /metrics
+	// handler is fed by a separate Prometheus sink (see setupTelemetry).
+	inmemSink *metrics.InmemSink
+
+	// metricsServer serves the Prometheus /metrics scrape endpoint on
+	// its own listener (Ports.Metrics).
+	metricsServer *http.Server
+
+	// metricSinks is the fanout built by the most recent setupTelemetry
+	// call, retained so a reload can shut down the previous remote
+	// sinks before replacing them.
+	metricSinks metrics.FanoutSink
+
+	// promCollector is the currently registered Prometheus collector,
+	// unregistered before setupTelemetry registers a replacement so a
+	// reload doesn't panic on a duplicate registration.
+	promCollector promclient.Collector
 }
 
 func (c *ServiceCommand) Help() string {
@@ -31,6 +75,8 @@ func (c *ServiceCommand) Run(args []string) int {
 		Ui:           c.Ui,
 	}
 
+	c.configReloadCh = make(chan chan error)
+
 	// Parse our configs
 	c.args = args
 	config := c.readConfig()
@@ -50,53 +96,53 @@ func (c *ServiceCommand) Run(args []string) int {
 		return 1
 	}
 
-	/* Setup telemetry
-	Aggregate on 10 second intervals for 1 minute. Expose the
-	metrics over stderr when there is a SIGUSR1 received.
-	*/
-	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
-	metrics.DefaultInmemSignal(inm)
-	metricsConf := metrics.DefaultConfig("consul")
-
-	// Optionally configure a statsite sink if provided
-	if config.StatsiteAddr != "" {
-		sink, err := metrics.NewStatsiteSink(config.StatsiteAddr)
-		if err != nil {
-			c.Ui.Error(fmt.Sprintf("Failed to start statsite sink. Got: %s", err))
-			return 1
-		}
-		fanout := metrics.FanoutSink{inm, sink}
-		metrics.NewGlobal(metricsConf, fanout)
+	// Setup telemetry. Aggregate on 10 second intervals for 1 minute,
+	// fan out to whichever backends are configured, expose the in-mem
+	// metrics over stderr when there is a SIGUSR1 received, and serve the
+	// Prometheus sink's metrics over HTTP when Prometheus scrapes /metrics.
+	inm, err := c.setupTelemetry(config)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	c.inmemSink = inm
 
-	} else {
-		metricsConf.EnableHostname = false
-		metrics.NewGlobal(metricsConf, inm)
+	if err := c.startMetricsServer(config); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
 	}
 
 	// Create the agent
 	if err := c.setupAgent(config, logOutput, logWriter); err != nil {
 		return 1
 	}
-	defer c.agent.Shutdown()
-	if c.rpcServer != nil {
-		defer c.rpcServer.Shutdown()
-	}
-	if c.httpServer != nil {
-		defer c.httpServer.Shutdown()
-	}
+
+	// Reap orphaned children if we're running as PID 1 (container use
+	// case), so health check exec plugins don't accumulate as zombies.
+	// Final shutdown of the agent/rpcServer/httpServer is centralized in
+	// handleSignals/handleShutdown below, not deferred here, so that a
+	// SIGINT/SIGTERM gets exactly one coordinated drain-then-force exit.
+	c.startReaper()
 
 	// Join startup nodes if specified
 	if err := c.startupJoin(config); err != nil {
 		c.Ui.Error(err.Error())
+		c.forceShutdown()
 		return 1
 	}
 
+	// Retry join runs in the background for the life of the agent, so
+	// nodes coming up in an autoscaling group without a seed list can
+	// still find the cluster.
+	go c.retryJoin(config)
+
 	// Register the services
 	for _, service := range config.Services {
 		ns := service.NodeService()
 		chkType := service.CheckType()
 		if err := c.agent.AddService(ns, chkType); err != nil {
 			c.Ui.Error(fmt.Sprintf("Failed to register service '%s': %v", service.Name, err))
+			c.forceShutdown()
 			return 1
 		}
 	}
@@ -107,6 +153,7 @@ func (c *ServiceCommand) Run(args []string) int {
 		chkType := &check.CheckType
 		if err := c.agent.AddCheck(health, chkType); err != nil {
 			c.Ui.Error(fmt.Sprintf("Failed to register check '%s': %v %v", check.Name, err, check))
+			c.forceShutdown()
 			return 1
 		}
 	}
@@ -142,7 +189,7 @@ func (c *ServiceCommand) Synopsis() string {
 // the command line and any file configs
 func (c *ServiceCommand) readConfig() *Config {
 	var cmdConfig Config
-	//var configFiles []string
+	var configFiles []string
 	cmdFlags := flag.NewFlagSet("agent", flag.ContinueOnError)
 	cmdFlags.Usage = func() { c.Ui.Output(c.Help()) }
 
@@ -151,23 +198,39 @@ func (c *ServiceCommand) readConfig() *Config {
 
 	cmdFlags.StringVar(&cmdConfig.BindAddr, "bind", "", "address to bind server listeners to")
 
+	cmdFlags.Var((*AppendSliceValue)(&configFiles), "config-file",
+		"json/hcl file to read config from")
+	cmdFlags.Var((*AppendSliceValue)(&configFiles), "config-dir",
+		"directory of json/hcl files to read")
+
+	var retryJoin AppendSliceValue
+	cmdFlags.Var(&retryJoin, "retry-join",
+		"address, or discovery provider string, to join with retry until it succeeds")
+	cmdFlags.IntVar(&cmdConfig.RetryMaxAttempts, "retry-max", 0,
+		"number of retry-join attempts before giving up (0 = infinite)")
+	cmdFlags.DurationVar(&cmdConfig.RetryInterval, "retry-interval", 0,
+		"time to wait between retry-join attempts")
+
+	cmdFlags.BoolVar(&cmdConfig.SkipLeaveOnInt, "skip-leave-on-interrupt", false,
+		"skip the graceful leave when receiving SIGINT")
+	cmdFlags.BoolVar(&cmdConfig.LeaveOnTerm, "leave-on-terminate", false,
+		"perform a graceful leave when receiving SIGTERM")
+	cmdFlags.DurationVar(&cmdConfig.LeaveDrainTimeout, "leave-drain-timeout", 0,
+		"time to wait for a graceful leave to finish before forcing shutdown")
+
 	if err := cmdFlags.Parse(c.args); err != nil {
 		return nil
 	}
 
-	config := DefaultConfig()
-	/*
-		if len(configFiles) > 0 {
-			fileConfig, err := ReadConfigPaths(configFiles)
-			if err != nil {
-				c.Ui.Error(err.Error())
-				return nil
-			}
-
-			config = MergeConfig(config, fileConfig)
-		}
-		config = MergeConfig(config, &cmdConfig)
-	*/
+	c.configFiles = configFiles
+	cmdConfig.RetryJoin = retryJoin
+	c.cmdConfig = &cmdConfig
+
+	config, err := c.mergedConfig()
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return nil
+	}
 
 	if config.NodeName == "" {
 		hostname, err := os.Hostname()
@@ -186,6 +249,96 @@ func (c *ServiceCommand) readConfig() *Config {
 	return config
 }
 
-func (c *ServiceCommand) setupLogger(config *Config) *slog.Logger {
-	return nil
+// mergedConfig layers c.cmdConfig (the CLI-flag overrides the agent was
+// started with) on top of DefaultConfig and the current
+// -config-file/-config-dir set, the same precedence readConfig applies
+// at startup. handleReload calls this too, so a SIGHUP re-reads the
+// files but still reapplies the original CLI overrides rather than
+// letting them silently revert to the file/default value.
+func (c *ServiceCommand) mergedConfig() (*Config, error) {
+	config := DefaultConfig()
+	if len(c.configFiles) > 0 {
+		fileConfig, err := ReadConfigPaths(c.configFiles)
+		if err != nil {
+			return nil, err
+		}
+		config = MergeConfig(config, fileConfig)
+	}
+
+	return MergeConfig(config, c.cmdConfig), nil
+}
+
+// setupLoggers builds the log output pipeline: a gate that buffers the
+// console output until the startup banner has printed (the log file and
+// syslog sinks are not gated), and a fanout to
+// stderr plus an optional rotating log file and syslog sink, all gated
+// together by a single level filter keyed off config.LogLevel (mutable
+// later via reload) so every sink honors the same level, not just the
+// console. logWriter is what gets handed to setupLogger to build the
+// agent's *slog.Logger; logOutput is the same pipeline exposed for
+// packages (like Serf) that want a raw io.Writer.
+func (c *ServiceCommand) setupLoggers(config *Config) (*logger.GatedWriter, io.Writer, io.Writer) {
+	logGate := &logger.GatedWriter{Writer: &uiWriter{ui: c.Ui}}
+
+	if !logger.ValidLevel(config.LogLevel) {
+		c.Ui.Error(fmt.Sprintf("Invalid log level %q. Valid log levels are: %v",
+			config.LogLevel, logger.Levels))
+		return nil, nil, nil
+	}
+
+	writers := []io.Writer{logGate}
+
+	if config.LogFile != "" {
+		fileWriter, err := logger.NewRotateWriter(config.LogFile, config.LogRotateBytes)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to set up log file: %s", err))
+			return nil, nil, nil
+		}
+		writers = append(writers, fileWriter)
+	}
+
+	if config.EnableSyslog {
+		syslogWriter, err := logger.NewSyslogWriter(config.SyslogFacility, "consul")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Syslog setup failed: %s", err))
+			return nil, nil, nil
+		}
+		writers = append(writers, syslogWriter)
+	}
+
+	c.logFilter = logger.NewLevelFilter(config.LogLevel, io.MultiWriter(writers...))
+	return logGate, c.logFilter, c.logFilter
+}
+
+// setupLogger builds the agent's *slog.Logger on top of the writer
+// pipeline assembled by setupLoggers.
+func (c *ServiceCommand) setupLogger(output io.Writer, config *Config) *slog.Logger {
+	handler := slog.NewTextHandler(output, &slog.HandlerOptions{
+		Level: slogLevel(config.LogLevel),
+	})
+	return slog.New(handler)
+}
+
+func slogLevel(level string) slog.Level {
+	switch strings.ToUpper(level) {
+	case "TRACE", "DEBUG":
+		return slog.LevelDebug
+	case "WARN":
+		return slog.LevelWarn
+	case "ERROR":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// uiWriter adapts a cli.Ui into an io.Writer so it can sit behind the
+// gate/level-filter pipeline like any other sink.
+type uiWriter struct {
+	ui cli.Ui
+}
+
+func (u *uiWriter) Write(p []byte) (int, error) {
+	u.ui.Output(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
 }
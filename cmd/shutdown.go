@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// handleShutdown is invoked from handleSignals on SIGINT/SIGTERM. It
+// optionally performs a graceful Leave (deregistering services and
+// gossiping a leave intent) before forcing the RPC server, HTTP server,
+// and agent down, waiting at most config.LeaveDrainTimeout for the Leave
+// to finish. A repeated SIGINT/SIGTERM received while draining forces an
+// immediate shutdown; other signals (e.g. SIGUSR1) are handled without
+// interrupting the drain. It returns 0 if the leave completed (or wasn't
+// attempted) cleanly, or 1 if shutdown had to be forced by a repeated
+// signal or a drain timeout.
+func (c *ServiceCommand) handleShutdown(sig os.Signal, config *Config, signalCh <-chan os.Signal) int {
+	graceful := true
+	if sig == os.Interrupt && config.SkipLeaveOnInt {
+		graceful = false
+	}
+	if sig == syscall.SIGTERM && !config.LeaveOnTerm {
+		graceful = false
+	}
+
+	doneCh := make(chan struct{})
+	if graceful && c.agent != nil {
+		c.Ui.Output("Gracefully shutting down agent...")
+		go func() {
+			defer close(doneCh)
+			if err := c.agent.Leave(); err != nil {
+				c.Ui.Error(fmt.Sprintf("Error leaving: %s", err))
+			}
+		}()
+	} else {
+		c.Ui.Output("Forcing shutdown, agent will not gossip a leave")
+		close(doneCh)
+	}
+
+	drainTimeout := config.LeaveDrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = 5 * time.Second
+	}
+	timeout := time.NewTimer(drainTimeout)
+	defer timeout.Stop()
+
+	forced := false
+wait:
+	for {
+		select {
+		case <-doneCh:
+			break wait
+		case s := <-signalCh:
+			switch s {
+			case os.Interrupt, syscall.SIGTERM:
+				c.Ui.Error(fmt.Sprintf("Received %s during drain, forcing shutdown", s))
+				forced = true
+				break wait
+			case syscall.SIGUSR1:
+				c.dumpMetrics()
+			}
+		case <-timeout.C:
+			c.Ui.Error("Timed out waiting for graceful leave, forcing shutdown")
+			forced = true
+			break wait
+		}
+	}
+
+	c.forceShutdown()
+	if forced {
+		return 1
+	}
+	return 0
+}
+
+// forceShutdown tears down the RPC server, HTTP server, and agent
+// without waiting for anything further. It is safe to call even if some
+// of these were never started.
+func (c *ServiceCommand) forceShutdown() {
+	if c.rpcServer != nil {
+		c.rpcServer.Shutdown()
+	}
+	if c.httpServer != nil {
+		c.httpServer.Shutdown()
+	}
+	if c.metricsServer != nil {
+		c.metricsServer.Close()
+	}
+	if c.agent != nil {
+		c.agent.Shutdown()
+	}
+}
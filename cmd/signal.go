@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handleSignals blocks waiting for an exit signal (or ShutdownCh), and
+// reacts to signals that don't terminate the process along the way: a
+// SIGUSR1 metrics dump, or a SIGHUP/configReloadCh-driven config reload.
+// SIGINT/SIGTERM (or a ShutdownCh close) hand off to handleShutdown for
+// a coordinated, drain-then-force exit.
+func (c *ServiceCommand) handleSignals(config *Config) int {
+	signalCh := make(chan os.Signal, 4)
+	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGHUP)
+
+	for {
+		select {
+		case sig := <-signalCh:
+			switch sig {
+			case syscall.SIGUSR1:
+				c.dumpMetrics()
+			case syscall.SIGHUP:
+				if newConfig, err := c.handleReload(config); err != nil {
+					c.Ui.Error(fmt.Sprintf("Reload error: %s", err))
+				} else {
+					config = newConfig
+				}
+			case os.Interrupt, syscall.SIGTERM:
+				return c.handleShutdown(sig, config, signalCh)
+			}
+
+		case replyCh := <-c.configReloadCh:
+			newConfig, err := c.handleReload(config)
+			if err == nil {
+				config = newConfig
+			}
+			replyCh <- err
+
+		case <-c.ShutdownCh:
+			return c.handleShutdown(os.Interrupt, config, signalCh)
+		}
+	}
+}
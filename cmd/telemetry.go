@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/armon/go-metrics/datadog"
+	"github.com/armon/go-metrics/prometheus"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// setupTelemetry is used to set up the telemetry sub-systems. It always
+// enables the in-memory sink (for SIGUSR1 introspection) and then fans
+// out to whichever backends are configured under Telemetry. The returned
+// InmemSink is retained by the caller so it can be dumped on SIGUSR1.
+func (c *ServiceCommand) setupTelemetry(config *Config) (*metrics.InmemSink, error) {
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	metrics.DefaultInmemSignal(inm)
+
+	metricsConf := metrics.DefaultConfig(config.Telemetry.MetricsPrefix)
+	if metricsConf.ServiceName == "" {
+		metricsConf.ServiceName = "consul"
+	}
+	metricsConf.EnableHostname = !config.Telemetry.DisableHostname
+
+	sinks := metrics.FanoutSink{inm}
+
+	if config.Telemetry.StatsiteAddr != "" {
+		sink, err := metrics.NewStatsiteSink(config.Telemetry.StatsiteAddr)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to start statsite sink: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if config.Telemetry.StatsdAddr != "" {
+		sink, err := metrics.NewStatsdSink(config.Telemetry.StatsdAddr)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to start statsd sink: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if config.Telemetry.DogStatsdAddr != "" {
+		sink, err := datadog.NewDogStatsdSink(config.Telemetry.DogStatsdAddr, config.NodeName)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to start dogstatsd sink: %v", err)
+		}
+		sink.SetTags(config.Telemetry.DogStatsdTags)
+		sinks = append(sinks, sink)
+	}
+
+	promSink, err := prometheus.NewPrometheusSink()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to start prometheus sink: %v", err)
+	}
+	if c.promCollector != nil {
+		promclient.Unregister(c.promCollector)
+	}
+	if err := promclient.Register(promSink); err != nil {
+		return nil, fmt.Errorf("Failed to register prometheus sink: %v", err)
+	}
+	c.promCollector = promSink
+	sinks = append(sinks, promSink)
+
+	c.shutdownMetricSinks()
+	c.metricSinks = sinks
+	metrics.NewGlobal(metricsConf, sinks)
+
+	return inm, nil
+}
+
+// shutdownMetricSinks closes out the sinks from the previous
+// setupTelemetry call that support it (remote sinks like statsite/statsd
+// hold an open connection and a background goroutine), so replacing them
+// on reload doesn't leak one set per reload.
+func (c *ServiceCommand) shutdownMetricSinks() {
+	for _, sink := range c.metricSinks {
+		if s, ok := sink.(metrics.ShutdownSink); ok {
+			s.Shutdown()
+		}
+	}
+}
+
+// startMetricsServer mounts the Prometheus scrape handler on its own
+// listener (Ports.Metrics), rather than just building a handler nothing
+// ever serves. It is deliberately not on Ports.HTTP: that's the real
+// HTTP API's listener (set up in setupAgent), and two servers can't bind
+// the same address.
+func (c *ServiceCommand) startMetricsServer(config *Config) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	addr := fmt.Sprintf("%s:%d", config.BindAddr, config.Ports.Metrics)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("Failed to start metrics listener: %v", err)
+	}
+
+	c.metricsServer = &http.Server{Addr: addr, Handler: mux}
+	go c.metricsServer.Serve(ln)
+
+	return nil
+}
+
+// dumpMetrics is invoked on SIGUSR1 to print the current contents of the
+// in-memory metrics sink to stderr, for on-demand introspection without
+// needing a configured backend.
+func (c *ServiceCommand) dumpMetrics() {
+	if c.inmemSink == nil {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	for _, interval := range c.inmemSink.Data() {
+		fmt.Fprintf(buf, "[%v]\n", interval.Interval)
+
+		for name, val := range interval.Gauges {
+			fmt.Fprintf(buf, "  [G] %s: %0.3f\n", name, val.Value)
+		}
+		for name, agg := range interval.Counters {
+			fmt.Fprintf(buf, "  [C] %s: %s\n", name, agg.AggregateSample)
+		}
+		for name, agg := range interval.Samples {
+			fmt.Fprintf(buf, "  [S] %s: %s\n", name, agg.AggregateSample)
+		}
+	}
+
+	c.Ui.Info(buf.String())
+}
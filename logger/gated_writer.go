@@ -0,0 +1,45 @@
+// Package logger provides the log output plumbing used by the agent
+// command: a gate that buffers output until the startup banner has
+// printed, a level filter, and sinks (syslog, rotating file) to fan out
+// to.
+package logger
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// GatedWriter buffers everything written to it until Flush is called,
+// at which point the buffered data is written through to the underlying
+// Writer and all further writes pass through directly. This keeps early
+// log lines from interleaving with the startup banner.
+type GatedWriter struct {
+	Writer io.Writer
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	flushed bool
+}
+
+// Flush writes any buffered output to the underlying Writer and stops
+// buffering. It is safe to call more than once.
+func (g *GatedWriter) Flush() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.flushed {
+		return
+	}
+	g.flushed = true
+	g.Writer.Write(g.buf.Bytes())
+	g.buf.Reset()
+}
+
+func (g *GatedWriter) Write(p []byte) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.flushed {
+		return g.Writer.Write(p)
+	}
+	return g.buf.Write(p)
+}
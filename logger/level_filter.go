@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Levels are the recognized log levels, lowest severity first, matching
+// the logutils-style "[LEVEL] message" tag. slog's text handler writes
+// "level=INFO" instead, which is not recognized as a level tag.
+var Levels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"}
+
+// LevelFilter is a logutils-style io.Writer: it drops any line whose
+// "[LEVEL]" prefix is below MinLevel, and passes everything else (and
+// any line it doesn't recognize a level for) through to Writer.
+// MinLevel can be changed at runtime, e.g. from a config reload, via
+// SetMinLevel.
+type LevelFilter struct {
+	Writer io.Writer
+
+	mu    sync.RWMutex
+	level string
+}
+
+// NewLevelFilter creates a LevelFilter writing to w, gated at minLevel.
+func NewLevelFilter(minLevel string, w io.Writer) *LevelFilter {
+	return &LevelFilter{Writer: w, level: strings.ToUpper(minLevel)}
+}
+
+// ValidLevel reports whether level is one of the recognized Levels.
+func ValidLevel(level string) bool {
+	level = strings.ToUpper(level)
+	for _, l := range Levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMinLevel changes the minimum level that passes the filter.
+func (f *LevelFilter) SetMinLevel(level string) error {
+	if !ValidLevel(level) {
+		return fmt.Errorf("invalid log level %q, must be one of: %v", level, Levels)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.level = strings.ToUpper(level)
+	return nil
+}
+
+// MinLevel returns the currently configured minimum level.
+func (f *LevelFilter) MinLevel() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.level
+}
+
+func (f *LevelFilter) Write(p []byte) (int, error) {
+	if !f.check(p) {
+		return len(p), nil
+	}
+	return f.Writer.Write(p)
+}
+
+// check reports whether a line should be written: lines tagged with a
+// recognized level pass only at or above MinLevel, lines with no
+// recognizable level tag always pass.
+func (f *LevelFilter) check(line []byte) bool {
+	minIdx := indexOf(Levels, f.MinLevel())
+	if minIdx == -1 {
+		return true
+	}
+
+	for i, level := range Levels {
+		if bytes.Contains(line, []byte("["+level+"]")) {
+			return i >= minIdx
+		}
+	}
+	return true
+}
+
+func indexOf(levels []string, level string) int {
+	for i, l := range levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLevelFilter_Check(t *testing.T) {
+	cases := []struct {
+		name     string
+		minLevel string
+		line     string
+		want     bool
+	}{
+		{"above min passes", "WARN", "[ERROR] boom", true},
+		{"equal to min passes", "WARN", "[WARN] careful", true},
+		{"below min is dropped", "WARN", "[INFO] hello", false},
+		{"below min is dropped (debug)", "INFO", "[DEBUG] chatty", false},
+		{"unrecognized level passes", "ERROR", "no level tag here", true},
+		{"lowest min passes everything", "TRACE", "[TRACE] tick", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			f := NewLevelFilter(tc.minLevel, &buf)
+
+			if got := f.check([]byte(tc.line)); got != tc.want {
+				t.Fatalf("check(%q) with minLevel %q = %v, want %v", tc.line, tc.minLevel, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLevelFilter_Write(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewLevelFilter("WARN", &buf)
+
+	f.Write([]byte("[INFO] dropped\n"))
+	if buf.Len() != 0 {
+		t.Fatalf("expected INFO line to be dropped, got %q", buf.String())
+	}
+
+	f.Write([]byte("[ERROR] kept\n"))
+	if buf.String() != "[ERROR] kept\n" {
+		t.Fatalf("expected ERROR line to pass through, got %q", buf.String())
+	}
+}
+
+func TestLevelFilter_SetMinLevel(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewLevelFilter("ERROR", &buf)
+
+	if err := f.SetMinLevel("bogus"); err == nil {
+		t.Fatal("expected an error for an invalid level")
+	}
+	if f.MinLevel() != "ERROR" {
+		t.Fatalf("MinLevel should be unchanged after a rejected SetMinLevel, got %q", f.MinLevel())
+	}
+
+	if err := f.SetMinLevel("debug"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if f.MinLevel() != "DEBUG" {
+		t.Fatalf("expected MinLevel to be normalized to DEBUG, got %q", f.MinLevel())
+	}
+
+	f.Write([]byte("[DEBUG] now visible\n"))
+	if buf.String() != "[DEBUG] now visible\n" {
+		t.Fatalf("expected DEBUG line to pass after lowering MinLevel, got %q", buf.String())
+	}
+}
+
+func TestValidLevel(t *testing.T) {
+	if !ValidLevel("info") {
+		t.Fatal("expected lowercase info to be valid")
+	}
+	if ValidLevel("VERBOSE") {
+		t.Fatal("expected an unrecognized level to be invalid")
+	}
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// RotateWriter appends to a log file on disk, rotating the file (moving
+// it aside with a timestamp suffix and starting a new one) once it
+// exceeds RotateBytes. A RotateBytes of 0 disables rotation.
+type RotateWriter struct {
+	path        string
+	rotateBytes int64
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotateWriter opens (or creates) path for appending, rotating once
+// its size exceeds rotateBytes.
+func NewRotateWriter(path string, rotateBytes int64) (*RotateWriter, error) {
+	w := &RotateWriter{path: path, rotateBytes: rotateBytes}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotateWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %q: %s", w.path, err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = fi.Size()
+	return nil
+}
+
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.rotateBytes > 0 && w.size+int64(len(p)) > w.rotateBytes {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotateWriter) rotate() error {
+	w.file.Close()
+
+	rotated := fmt.Sprintf("%s.%d", w.path, time.Now().UnixNano())
+	if err := os.Rename(w.path, rotated); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return w.open()
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotateWriter_NoRotationWhenDisabled(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "consul.log")
+
+	w, err := NewRotateWriter(path, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := w.Write([]byte("0123456789")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := w.Write([]byte("0123456789")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected no rotation to have happened, got %d files: %v", len(entries), entries)
+	}
+}
+
+func TestRotateWriter_RotatesAtBoundary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "consul.log")
+
+	w, err := NewRotateWriter(path, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Exactly at the boundary: this write alone is not over the limit,
+	// so it should not rotate yet.
+	if _, err := w.Write([]byte("0123456789")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly one file at the boundary, got %d: %v", len(entries), entries)
+	}
+
+	// This write pushes us over the limit, so it should rotate the
+	// existing file aside before writing to a fresh one.
+	if _, err := w.Write([]byte("x")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	entries, err = os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected a rotation to have produced a second file, got %d: %v", len(entries), entries)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(data) != "x" {
+		t.Fatalf("expected the current log file to contain only the post-rotation write, got %q", data)
+	}
+}
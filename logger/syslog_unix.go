@@ -0,0 +1,66 @@
+//go:build !windows
+
+package logger
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+)
+
+// SyslogWriter adapts the stdlib syslog writer to io.Writer so it can be
+// combined with the other log sinks via io.MultiWriter.
+type SyslogWriter struct {
+	w *syslog.Writer
+}
+
+// NewSyslogWriter opens a syslog connection logging under the given
+// facility (e.g. "LOCAL0") and tag.
+func NewSyslogWriter(facility, tag string) (*SyslogWriter, error) {
+	priority, err := facilityPriority(facility)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := syslog.New(priority|syslog.LOG_NOTICE, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open syslog: %s", err)
+	}
+
+	return &SyslogWriter{w: w}, nil
+}
+
+func (s *SyslogWriter) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
+
+func facilityPriority(facility string) (syslog.Priority, error) {
+	facilities := map[string]syslog.Priority{
+		"KERN":     syslog.LOG_KERN,
+		"USER":     syslog.LOG_USER,
+		"MAIL":     syslog.LOG_MAIL,
+		"DAEMON":   syslog.LOG_DAEMON,
+		"AUTH":     syslog.LOG_AUTH,
+		"SYSLOG":   syslog.LOG_SYSLOG,
+		"LPR":      syslog.LOG_LPR,
+		"NEWS":     syslog.LOG_NEWS,
+		"UUCP":     syslog.LOG_UUCP,
+		"CRON":     syslog.LOG_CRON,
+		"AUTHPRIV": syslog.LOG_AUTHPRIV,
+		"FTP":      syslog.LOG_FTP,
+		"LOCAL0":   syslog.LOG_LOCAL0,
+		"LOCAL1":   syslog.LOG_LOCAL1,
+		"LOCAL2":   syslog.LOG_LOCAL2,
+		"LOCAL3":   syslog.LOG_LOCAL3,
+		"LOCAL4":   syslog.LOG_LOCAL4,
+		"LOCAL5":   syslog.LOG_LOCAL5,
+		"LOCAL6":   syslog.LOG_LOCAL6,
+		"LOCAL7":   syslog.LOG_LOCAL7,
+	}
+
+	p, ok := facilities[strings.ToUpper(facility)]
+	if !ok {
+		return 0, fmt.Errorf("invalid syslog facility: %q", facility)
+	}
+	return p, nil
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package logger
+
+import "fmt"
+
+// SyslogWriter is unavailable on Windows, which has no syslog daemon.
+type SyslogWriter struct{}
+
+// NewSyslogWriter always returns an error on Windows.
+func NewSyslogWriter(facility, tag string) (*SyslogWriter, error) {
+	return nil, fmt.Errorf("syslog is not supported on this platform")
+}
+
+func (s *SyslogWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}